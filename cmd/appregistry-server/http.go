@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/operator-framework/operator-registry/pkg/metrics"
+	"github.com/operator-framework/operator-registry/pkg/server"
+)
+
+// newGatewayMux returns an http.Handler that exposes api.Registry as
+// REST+JSON, for operators who want to poke at a registry pod with curl or
+// a browser instead of vendoring the gRPC stubs. Every route is a thin
+// translation onto the same mutableStore the gRPC server reads from, so
+// both listeners are always consistent with each other and with a
+// concurrent background refresh.
+//
+// Routes:
+//
+//	GET /v1/packages                                         ListPackages (ndjson)
+//	GET /v1/packages/{name}                                  GetPackage
+//	GET /v1/packages/{name}/channels/{channel}/bundle         GetBundleForChannel
+//	GET /v1/packages/{name}/channels/{channel}/csvs/{csv}/bundle  GetBundle
+//	GET /v1/bundles                                          ListBundles (ndjson)
+func newGatewayMux(mutableStore *server.MutableRegistryServer, logger logr.Logger) http.Handler {
+	g := &gateway{store: mutableStore}
+
+	return &router{routes: []route{
+		newRoute(http.MethodGet, `^/v1/packages$`, "/api.Registry/ListPackages", logger, g.listPackages),
+		newRoute(http.MethodGet, `^/v1/packages/(?P<name>[^/]+)$`, "/api.Registry/GetPackage", logger, g.getPackage),
+		newRoute(http.MethodGet, `^/v1/packages/(?P<name>[^/]+)/channels/(?P<channel>[^/]+)/bundle$`, "/api.Registry/GetBundleForChannel", logger, g.getBundleForChannel),
+		newRoute(http.MethodGet, `^/v1/packages/(?P<name>[^/]+)/channels/(?P<channel>[^/]+)/csvs/(?P<csv>[^/]+)/bundle$`, "/api.Registry/GetBundle", logger, g.getBundle),
+		newRoute(http.MethodGet, `^/v1/bundles$`, "/api.Registry/ListBundles", logger, g.listBundles),
+	}}
+}
+
+// route pairs a method and path pattern with the handler that serves it.
+// pattern is matched against the whole of r.URL.Path (it's always anchored
+// with ^...$), and any named capture groups are made available to the
+// handler through pathValue.
+type route struct {
+	method  string
+	path    *regexp.Regexp
+	handler http.HandlerFunc
+}
+
+// newRoute builds a route whose handler is wrapped with the same
+// metrics/logging middleware every gRPC method gets, keyed by grpcMethod -
+// the api.Registry method this HTTP route mirrors.
+func newRoute(method, pattern, grpcMethod string, logger logr.Logger, handler http.HandlerFunc) route {
+	return route{
+		method:  method,
+		path:    regexp.MustCompile(pattern),
+		handler: metrics.HTTPMiddleware(grpcMethod, httpLoggingMiddleware(logger, grpcMethod, handler)),
+	}
+}
+
+// router is a minimal method+path dispatcher. net/http's own ServeMux grew
+// method-prefixed patterns and path wildcards in Go 1.22, but this checkout
+// has no go.mod to pin a minimum toolchain version, so routing can't rely on
+// that: pre-1.22 the method prefix isn't parsed and every route would 404.
+// Matching by hand here works on any Go version this package otherwise
+// builds with.
+type router struct {
+	routes []route
+}
+
+// pathParamsKey is the context key router stores a matched route's named
+// capture groups under.
+type pathParamsKey struct{}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, rte := range rt.routes {
+		if rte.method != r.Method {
+			continue
+		}
+		match := rte.path.FindStringSubmatch(r.URL.Path)
+		if match == nil {
+			continue
+		}
+		params := make(map[string]string, len(match)-1)
+		for i, name := range rte.path.SubexpNames() {
+			if i == 0 || name == "" {
+				continue
+			}
+			params[name] = match[i]
+		}
+		rte.handler(w, r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params)))
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// pathValue returns the value the matched route captured under name, or ""
+// if there was no such capture group - the regexp.Regexp equivalent of
+// http.Request.PathValue.
+func pathValue(r *http.Request, name string) string {
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	return params[name]
+}
+
+// gateway holds the dependencies every route handler needs.
+type gateway struct {
+	store *server.MutableRegistryServer
+}
+
+func (g *gateway) getPackage(w http.ResponseWriter, r *http.Request) {
+	pkg, err := g.store.GetPackage(r.Context(), pathValue(r, "name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, pkg)
+}
+
+func (g *gateway) getBundleForChannel(w http.ResponseWriter, r *http.Request) {
+	bundle, err := g.store.GetBundleForChannel(r.Context(), pathValue(r, "name"), pathValue(r, "channel"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, bundle)
+}
+
+func (g *gateway) getBundle(w http.ResponseWriter, r *http.Request) {
+	bundle, err := g.store.GetBundle(r.Context(), pathValue(r, "name"), pathValue(r, "channel"), pathValue(r, "csv"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, bundle)
+}
+
+func (g *gateway) listPackages(w http.ResponseWriter, r *http.Request) {
+	names, err := g.store.ListPackages(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	enc := newNDJSONEncoder(w)
+	for _, name := range names {
+		if !enc.encode(struct {
+			Name string `json:"name"`
+		}{name}) {
+			return
+		}
+	}
+}
+
+func (g *gateway) listBundles(w http.ResponseWriter, r *http.Request) {
+	bundles, err := g.store.ListBundles(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	enc := newNDJSONEncoder(w)
+	for _, bundle := range bundles {
+		if !enc.encode(bundle) {
+			return
+		}
+	}
+}
+
+// writeJSON writes v as a single JSON document with a 200 status.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError maps err onto an HTTP status and writes it as a JSON body.
+// sql.ErrNoRows is what the sqlite-backed registry.Query returns for a
+// missing package, channel, or bundle, so it is the one case translated to
+// 404 rather than a generic 500.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, sql.ErrNoRows) {
+		status = http.StatusNotFound
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// ndjsonEncoder streams one JSON value per line, flushing after each so a
+// client sees packages/bundles arrive incrementally rather than waiting for
+// the whole list to buffer.
+type ndjsonEncoder struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+func newNDJSONEncoder(w http.ResponseWriter) *ndjsonEncoder {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	return &ndjsonEncoder{enc: json.NewEncoder(w), flusher: flusher}
+}
+
+// encode writes v followed by a newline and reports whether the write
+// succeeded, so callers can stop iterating once the client has gone away.
+func (e *ndjsonEncoder) encode(v interface{}) bool {
+	if err := e.enc.Encode(v); err != nil {
+		return false
+	}
+	if e.flusher != nil {
+		e.flusher.Flush()
+	}
+	return true
+}
+
+// httpLoggingMiddleware emits one structured record per request, mirroring
+// the fields loggingUnaryServerInterceptor logs for gRPC: method, peer,
+// duration, and code. Here code is the HTTP status number written, not a
+// gRPC code name.
+func httpLoggingMiddleware(logger logr.Logger, grpcMethod string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(sw, r)
+
+		logger.Info("handled rpc",
+			"method", grpcMethod,
+			"peer", r.RemoteAddr,
+			"duration", time.Since(start).String(),
+			"code", sw.statusCode,
+		)
+	}
+}
+
+// statusRecorder captures the status code a handler wrote, defaulting to
+// http.StatusOK for handlers that never call WriteHeader explicitly.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sw *statusRecorder) WriteHeader(code int) {
+	sw.statusCode = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes through to the wrapped writer, so ndjsonEncoder can still
+// flush each line through this wrapper.
+func (sw *statusRecorder) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// loggingUnaryServerInterceptor returns a grpc.UnaryServerInterceptor that
+// emits one structured record per RPC, carrying the method, peer, duration
+// and status code. Requests to the HTTP/JSON gateway do not pass through it;
+// httpLoggingMiddleware logs the same field names for those, with code set
+// to the HTTP status number.
+func loggingUnaryServerInterceptor(logger logr.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		addr := "unknown"
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			addr = p.Addr.String()
+		}
+
+		logger.Info("handled rpc",
+			"method", info.FullMethod,
+			"peer", addr,
+			"duration", time.Since(start).String(),
+			"code", status.Code(err).String(),
+		)
+
+		return resp, err
+	}
+}
@@ -1,8 +1,17 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
@@ -12,6 +21,7 @@ import (
 	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
 	"github.com/operator-framework/operator-registry/pkg/appregistry"
 	"github.com/operator-framework/operator-registry/pkg/lib/log"
+	"github.com/operator-framework/operator-registry/pkg/metrics"
 	"github.com/operator-framework/operator-registry/pkg/server"
 )
 
@@ -21,8 +31,10 @@ func main() {
 		Long:  `appregistry-server downloads operator manifest(s) from remote appregistry, builds a sqlite database containing these downloaded manifest(s) and serves a grpc API to query it`,
 
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// --debug is kept for backward compatibility as a shorthand for
+			// --log-level=debug.
 			if debug, _ := cmd.Flags().GetBool("debug"); debug {
-				logrus.SetLevel(logrus.DebugLevel)
+				return cmd.Flags().Set("log-level", "debug")
 			}
 			return nil
 		},
@@ -30,7 +42,9 @@ func main() {
 		RunE: runCmdFunc,
 	}
 
-	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().Bool("debug", false, "enable debug logging (shorthand for --log-level=debug)")
+	rootCmd.Flags().String("log-format", "json", "log output format: json or text")
+	rootCmd.Flags().String("log-level", "info", "minimum log severity: debug, info, warn, or error")
 	rootCmd.Flags().StringP("kubeconfig", "k", "", "absolute path to kubeconfig file")
 	rootCmd.Flags().StringP("database", "d", "bundles.db", "name of db to output")
 	rootCmd.Flags().StringSliceP("sources", "s", []string{}, "comma separated list of OperatorSource object(s) {namespace}/{name}")
@@ -38,23 +52,37 @@ func main() {
 	rootCmd.Flags().StringP("packages", "o", "", "comma separated list of package(s) to be downloaded from the specified operator source(s)")
 	rootCmd.Flags().StringP("port", "p", "50051", "port number to serve on")
 	rootCmd.Flags().StringP("termination-log", "t", "/dev/termination-log", "path to a container termination log file")
+	rootCmd.Flags().String("metrics-addr", ":8081", "address to serve Prometheus metrics on")
+	rootCmd.Flags().String("http-addr", "", "if set, serve the api.Registry service as REST+JSON on this address alongside the gRPC API")
+	rootCmd.Flags().String("tls-cert", "", "path to a PEM encoded TLS certificate, to serve the gRPC API over TLS")
+	rootCmd.Flags().String("tls-key", "", "path to a PEM encoded TLS private key, to serve the gRPC API over TLS")
+	rootCmd.Flags().String("tls-client-ca", "", "path to a PEM encoded CA bundle; when set, client certificates are required and verified against it (mTLS)")
+	rootCmd.Flags().String("tls-min-version", "1.2", "minimum TLS version to accept (1.0, 1.1, 1.2, 1.3)")
+	rootCmd.Flags().Duration("refresh-interval", 0, "if set, periodically reload manifests from the upstream appregistry into the serving store (0 disables background refresh)")
 
 	if err := rootCmd.Flags().MarkHidden("debug"); err != nil {
-		logrus.Panic(err.Error())
+		panic(err.Error())
 	}
 
 	if err := rootCmd.Execute(); err != nil {
-		logrus.Panic(err.Error())
+		panic(err.Error())
 	}
 }
 
 func runCmdFunc(cmd *cobra.Command, args []string) error {
-	// Immediately set up termination log
 	terminationLogPath, err := cmd.Flags().GetString("termination-log")
 	if err != nil {
 		return err
 	}
-	err = log.AddDefaultWriterHooks(terminationLogPath)
+	logFormat, err := cmd.Flags().GetString("log-format")
+	if err != nil {
+		return err
+	}
+	logLevel, err := cmd.Flags().GetString("log-level")
+	if err != nil {
+		return err
+	}
+	baseLogger, err := log.NewLogger(logFormat, logLevel)
 	if err != nil {
 		return err
 	}
@@ -80,37 +108,184 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	metricsAddr, err := cmd.Flags().GetString("metrics-addr")
+	if err != nil {
+		return err
+	}
+	httpAddr, err := cmd.Flags().GetString("http-addr")
+	if err != nil {
+		return err
+	}
+	tlsFlagValues, err := getTLSFlags(cmd)
+	if err != nil {
+		return err
+	}
+	refreshInterval, err := cmd.Flags().GetDuration("refresh-interval")
+	if err != nil {
+		return err
+	}
 
-	logger := logrus.WithFields(logrus.Fields{"type": "appregistry", "port": port})
+	logger := baseLogger.WithValues("type", "appregistry", "port", port)
+
+	go serveMetrics(metricsAddr, logger)
+
+	creds, tlsConfig, err := newServerTransportCredentials(tlsFlagValues, logger)
+	if err != nil {
+		return fatal(logger, terminationLogPath, err, "error configuring TLS")
+	}
 
-	loader, err := appregistry.NewLoader(kubeconfig, logger, legacy)
+	// appregistry.NewLoader still takes a logrus.FieldLogger: pkg/appregistry,
+	// the download loop behind Load() and the only other logrus call site
+	// this request was meant to convert, is not part of this checkout, so
+	// its internal logging has not actually been moved off logrus yet. Build
+	// it a logrus logger configured from the same --log-format/--log-level
+	// flags as logger, rather than changing what NewLoader accepts.
+	appregistryLogger, err := log.NewLegacyFieldLogger(logFormat, logLevel)
+	if err != nil {
+		return err
+	}
+	loader, err := appregistry.NewLoader(kubeconfig, appregistryLogger.WithFields(logrus.Fields{"type": "appregistry", "port": port}), legacy)
 	if err != nil {
-		logger.Fatalf("error initializing - %v", err)
+		return fatal(logger, terminationLogPath, err, "error initializing")
 	}
 
+	loadStart := time.Now()
 	store, err := loader.Load(dbName, sources, packages)
 	if err != nil {
-		logger.Fatalf("error loading manifest from remote registry - %v", err)
+		return fatal(logger, terminationLogPath, err, "error loading manifest from remote registry")
 	}
+	recordStoreLoad(context.Background(), store, loadStart)
+	mutableStore := server.NewMutableRegistryServer(store)
 
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		logger.Fatalf("failed to listen: %v", err)
+		return fatal(logger, terminationLogPath, err, "failed to listen")
 	}
-	s := grpc.NewServer()
+	serverOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		loggingUnaryServerInterceptor(logger),
+		metrics.UnaryServerInterceptor(),
+	)}
+	if creds != nil {
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	}
+	s := grpc.NewServer(serverOpts...)
 
-	api.RegisterRegistryServer(s, server.NewRegistryServer(store))
+	api.RegisterRegistryServer(s, server.NewRegistryServer(mutableStore))
 	health.RegisterHealthServer(s, server.NewHealthServer())
 	reflection.Register(s)
 
+	var httpServer *http.Server
+	if httpAddr != "" {
+		httpServer = &http.Server{
+			Addr:      httpAddr,
+			Handler:   newGatewayMux(mutableStore, logger),
+			TLSConfig: tlsConfig,
+		}
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				// ListenAndServeTLS reads the cert/key files itself, but
+				// TLSConfig.GetCertificate is already populated above, so
+				// passing empty paths here still serves through it.
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error(err, "HTTP/JSON gateway failed")
+			}
+		}()
+	}
+
+	var refreshWG sync.WaitGroup
+	stopRefresh := make(chan struct{})
+	if refreshInterval > 0 {
+		r := &refresher{
+			loader:   loader,
+			mutable:  mutableStore,
+			dbName:   dbName,
+			sources:  sources,
+			packages: packages,
+			interval: refreshInterval,
+			logger:   logger,
+		}
+		go r.run(stopRefresh, &refreshWG)
+	}
+
+	sigterm := make(chan os.Signal, 1)
+	signal.Notify(sigterm, syscall.SIGTERM)
+	go func() {
+		<-sigterm
+		logger.Info("received SIGTERM, shutting down gracefully")
+		close(stopRefresh)
+		if httpServer != nil {
+			_ = httpServer.Shutdown(context.Background())
+		}
+		s.GracefulStop()
+	}()
+
 	logger.Info("serving registry")
 	if err := s.Serve(lis); err != nil {
-		logger.Fatalf("failed to serve: %v", err)
+		return fatal(logger, terminationLogPath, err, "failed to serve")
 	}
+	refreshWG.Wait()
 
 	return nil
 }
 
+// fatal logs err as the reason appregistry-server is exiting, best-effort
+// records it to the container termination log so it survives past the
+// pod's log buffer, and returns it so RunE propagates a non-zero exit code.
+func fatal(logger logr.Logger, terminationLogPath string, err error, msg string) error {
+	logger.Error(err, msg)
+	if writeErr := log.WriteTerminationLog(terminationLogPath, fmt.Errorf("%s: %w", msg, err)); writeErr != nil {
+		logger.Error(writeErr, "failed to write termination log")
+	}
+	return err
+}
+
+// recordStoreLoad reads the package and bundle counts back out of store and
+// records them, along with the time since start, as the outcome of the
+// load that just produced it. It's a best-effort metric: a failure to read
+// the counts back is not treated as a load failure.
+func recordStoreLoad(ctx context.Context, store server.RegistryQuerier, start time.Time) {
+	pkgNames, err := store.ListPackages(ctx)
+	if err != nil {
+		return
+	}
+	bundles, err := store.ListBundles(ctx)
+	if err != nil {
+		return
+	}
+	metrics.RecordStoreLoad(len(pkgNames), len(bundles), time.Since(start))
+}
+
+// serveMetrics starts an HTTP server exposing Prometheus metrics on addr.
+// It is run in its own goroutine and logs (rather than returns) a failure,
+// since a scrape-endpoint outage should not take down the registry itself.
+func serveMetrics(addr string, logger logr.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error(err, "metrics server failed")
+	}
+}
+
+// getTLSFlags reads the --tls-* flags into a tlsFlags struct.
+func getTLSFlags(cmd *cobra.Command) (flags tlsFlags, err error) {
+	if flags.certFile, err = cmd.Flags().GetString("tls-cert"); err != nil {
+		return
+	}
+	if flags.keyFile, err = cmd.Flags().GetString("tls-key"); err != nil {
+		return
+	}
+	if flags.clientCAFile, err = cmd.Flags().GetString("tls-client-ca"); err != nil {
+		return
+	}
+	flags.minVersion, err = cmd.Flags().GetString("tls-min-version")
+	return
+}
+
 // Backward compatibility:
 // If the old flag 'sources' is specified then we return legacy as true. This
 // helps appregistry.NewLoader to instantiate the right 'source' parser.
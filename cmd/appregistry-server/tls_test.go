@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestTLSMinVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: tls.VersionTLS12},
+		{version: "1.0", want: tls.VersionTLS10},
+		{version: "1.1", want: tls.VersionTLS11},
+		{version: "1.2", want: tls.VersionTLS12},
+		{version: "1.3", want: tls.VersionTLS13},
+		{version: "1.4", wantErr: true},
+		{version: "bogus", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := tlsMinVersion(tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("tlsMinVersion(%q) returned nil error, want one", tt.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tlsMinVersion(%q) returned %v, want nil error", tt.version, err)
+			}
+			if got != tt.want {
+				t.Fatalf("tlsMinVersion(%q) = %#x, want %#x", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertReloaderReloadPicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	firstSerial := writeSelfSignedCert(t, certFile, keyFile, big.NewInt(1))
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload() = %v, want nil", err)
+	}
+
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() = %v, want nil error", err)
+	}
+	if got := cert.Leaf; got != nil && got.SerialNumber.Cmp(firstSerial) != 0 {
+		t.Fatalf("loaded certificate serial = %v, want %v", got.SerialNumber, firstSerial)
+	}
+
+	secondSerial := writeSelfSignedCert(t, certFile, keyFile, big.NewInt(2))
+	if err := r.reload(); err != nil {
+		t.Fatalf("reload() after rotation = %v, want nil", err)
+	}
+
+	cert, err = r.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetCertificate() after rotation = %v, want nil error", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse reloaded certificate: %v", err)
+	}
+	if leaf.SerialNumber.Cmp(secondSerial) != 0 {
+		t.Fatalf("reloaded certificate serial = %v, want %v (rotation was not picked up)", leaf.SerialNumber, secondSerial)
+	}
+}
+
+func TestCertReloaderRejectsMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := newCertReloader(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"), logr.Discard()); err == nil {
+		t.Fatal("newCertReloader() with missing files returned nil error, want one")
+	}
+}
+
+// writeSelfSignedCert writes a fresh self-signed ECDSA certificate/key pair
+// with the given serial number to certFile/keyFile, and returns the serial
+// so callers can confirm which generation GetCertificate is serving.
+func writeSelfSignedCert(t *testing.T, certFile, keyFile string, serial *big.Int) *big.Int {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "appregistry-server-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("failed to write cert file: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	return serial
+}
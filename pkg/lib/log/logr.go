@@ -0,0 +1,109 @@
+package log
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// terminationLogPerm matches the permissions Kubernetes expects to be able
+// to read back from a container's termination log file.
+const terminationLogPerm = 0o644
+
+// NewLogger builds the logr.Logger used throughout appregistry-server, so
+// every log site there is structured and machine-parseable (for
+// Loki/Elastic ingestion) regardless of which slog handler backs it.
+//
+// format selects the slog handler: "json" (the default) or "text".
+// level selects the minimum severity logged: "debug", "info" (the
+// default), "warn", or "error".
+func NewLogger(format, level string) (logr.Logger, error) {
+	slogLevel, err := parseLevel(level)
+	if err != nil {
+		return logr.Logger{}, err
+	}
+	opts := &slog.HandlerOptions{Level: slogLevel}
+
+	var handler slog.Handler
+	switch format {
+	case "", "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	case "text":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	default:
+		return logr.Logger{}, fmt.Errorf("unsupported log-format %q: must be \"json\" or \"text\"", format)
+	}
+
+	return logr.FromSlogHandler(handler), nil
+}
+
+// NewLegacyFieldLogger builds a logrus.FieldLogger configured from the same
+// format/level flags as NewLogger, for call sites that still require one -
+// currently just appregistry.NewLoader, whose logging pkg/appregistry has
+// not yet been converted off logrus. Once pkg/appregistry takes a
+// logr.Logger instead, this and its call site should both go away.
+func NewLegacyFieldLogger(format, level string) (logrus.FieldLogger, error) {
+	logrusLevel, err := legacyLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrusLevel)
+	switch format {
+	case "", "json":
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		logger.SetFormatter(&logrus.TextFormatter{})
+	default:
+		return nil, fmt.Errorf("unsupported log-format %q: must be \"json\" or \"text\"", format)
+	}
+
+	return logger, nil
+}
+
+// WriteTerminationLog records err as the reason the process is exiting, at
+// path (conventionally /dev/termination-log), so that the last error a pod
+// hit is visible via `kubectl describe pod` even though structured logs
+// have already scrolled out of the node's log buffer. A failure to write is
+// returned but otherwise ignorable - it must never stop the process from
+// exiting on the original error.
+func WriteTerminationLog(path string, err error) error {
+	if path == "" {
+		return nil
+	}
+	return os.WriteFile(path, []byte(err.Error()), terminationLogPerm)
+}
+
+func parseLevel(level string) (slog.Level, error) {
+	switch level {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unsupported log-level %q: must be one of debug, info, warn, error", level)
+	}
+}
+
+func legacyLevel(level string) (logrus.Level, error) {
+	switch level {
+	case "", "info":
+		return logrus.InfoLevel, nil
+	case "debug":
+		return logrus.DebugLevel, nil
+	case "warn":
+		return logrus.WarnLevel, nil
+	case "error":
+		return logrus.ErrorLevel, nil
+	default:
+		return 0, fmt.Errorf("unsupported log-level %q: must be one of debug, info, warn, error", level)
+	}
+}
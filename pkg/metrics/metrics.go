@@ -0,0 +1,196 @@
+// Package metrics defines the Prometheus collectors published by
+// appregistry-server and exposes helpers for the appregistry loader and
+// the gRPC registry server to record against them.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ManifestDownloadsTotal counts manifest downloads attempted per
+	// OperatorSource.
+	ManifestDownloadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appregistry_manifest_downloads_total",
+		Help: "Number of manifest downloads attempted, by operator source.",
+	}, []string{"source"})
+
+	// ManifestDownloadErrorsTotal counts manifest downloads that failed per
+	// OperatorSource.
+	ManifestDownloadErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appregistry_manifest_download_errors_total",
+		Help: "Number of manifest downloads that failed, by operator source.",
+	}, []string{"source"})
+
+	// PackageLoadDuration observes how long it takes to download and unpack
+	// a single package's manifest.
+	PackageLoadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "appregistry_package_load_duration_seconds",
+		Help:    "Time taken to download and unpack a single package's manifest.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"package"})
+
+	// BundlesLoaded is the number of bundles written to the sqlite DB by the
+	// most recent load.
+	BundlesLoaded = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "appregistry_bundles_loaded",
+		Help: "Number of bundles written to the database by the most recent load.",
+	})
+
+	// PackagesLoaded is the number of packages written to the sqlite DB by
+	// the most recent load.
+	PackagesLoaded = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "appregistry_packages_loaded",
+		Help: "Number of packages written to the database by the most recent load.",
+	})
+
+	// ChannelsLoaded is the number of channels written to the sqlite DB by
+	// the most recent load.
+	ChannelsLoaded = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "appregistry_channels_loaded",
+		Help: "Number of channels written to the database by the most recent load.",
+	})
+
+	// DBBuildDuration observes how long a full load-and-build of the sqlite
+	// DB took.
+	DBBuildDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "appregistry_db_build_duration_seconds",
+		Help:    "Time taken to build the sqlite database from downloaded manifests.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	// LastSuccessfulLoadTimestamp is the unix time of the last load that
+	// completed without error. Scrapers can alert when this stops advancing.
+	LastSuccessfulLoadTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "appregistry_last_successful_load_timestamp_seconds",
+		Help: "Unix timestamp of the last successful manifest load.",
+	})
+
+	// RefreshFailuresTotal counts background --refresh-interval reloads that
+	// failed and were discarded, leaving the previously-serving store in
+	// place.
+	RefreshFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "appregistry_refresh_failures_total",
+		Help: "Number of background store refreshes that failed.",
+	})
+
+	rpcRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appregistry_grpc_requests_total",
+		Help: "Number of registry requests received over gRPC or the HTTP/JSON gateway, by method and status code.",
+	}, []string{"method", "code"})
+
+	rpcRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "appregistry_grpc_request_duration_seconds",
+		Help:    "Latency of registry requests over gRPC or the HTTP/JSON gateway, by method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// RecordLoad records the outcome of a Loader.Load call: the counts written
+// to the database and the time the build took. Call it unconditionally on
+// success so LastSuccessfulLoadTimestamp advances.
+//
+// ManifestDownloadsTotal, ManifestDownloadErrorsTotal and
+// PackageLoadDuration are deliberately not touched here - they're
+// per-OperatorSource and per-package, so they can only be recorded from
+// inside the download loop that iterates sources and packages, in
+// pkg/appregistry. That package is not part of this checkout, so those
+// three collectors have no call site yet; wire them in when it is.
+func RecordLoad(packages, bundles, channels int, duration time.Duration) {
+	PackagesLoaded.Set(float64(packages))
+	BundlesLoaded.Set(float64(bundles))
+	ChannelsLoaded.Set(float64(channels))
+	DBBuildDuration.Observe(duration.Seconds())
+	LastSuccessfulLoadTimestamp.SetToCurrentTime()
+}
+
+// RecordStoreLoad is RecordLoad for callers that only have a RegistryQuerier
+// to read counts back from after the fact (cmd/appregistry-server's initial
+// load and its periodic refresh), rather than exact counts from inside the
+// loader as it builds the database. It covers every field RecordLoad does
+// except ChannelsLoaded: channel counts aren't observable through
+// server.RegistryQuerier, so that gauge still needs wiring from inside the
+// loader/builder once pkg/appregistry is converted to report it directly.
+func RecordStoreLoad(packages, bundles int, duration time.Duration) {
+	PackagesLoaded.Set(float64(packages))
+	BundlesLoaded.Set(float64(bundles))
+	DBBuildDuration.Observe(duration.Seconds())
+	LastSuccessfulLoadTimestamp.SetToCurrentTime()
+}
+
+// Handler returns the http.Handler that should be mounted at /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that counts
+// and times every RPC served by the registry server.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		rpcRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		rpcRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// HTTPMiddleware wraps next, recording its outcome against the very same
+// appregistry_grpc_requests_total/appregistry_grpc_request_duration_seconds
+// series UnaryServerInterceptor uses, labelled with grpcMethod - the
+// api.Registry method the HTTP route mirrors - so a request for, say,
+// GetPackage shows up under one series whether it came in over gRPC or the
+// HTTP/JSON gateway.
+func HTTPMiddleware(grpcMethod string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next(sw, r)
+		rpcRequestDuration.WithLabelValues(grpcMethod).Observe(time.Since(start).Seconds())
+		rpcRequestsTotal.WithLabelValues(grpcMethod, httpStatusCode(sw.statusCode).String()).Inc()
+	}
+}
+
+// statusWriter records the status code written through it, defaulting to
+// http.StatusOK for handlers that never call WriteHeader explicitly.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.statusCode = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Flush passes through to the wrapped writer when it supports flushing.
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// httpStatusCode maps an HTTP status to the grpc.Code its gRPC counterpart
+// would have returned for the same outcome, so the "code" label means the
+// same thing regardless of which listener served the request.
+func httpStatusCode(httpStatus int) codes.Code {
+	switch {
+	case httpStatus < 400:
+		return codes.OK
+	case httpStatus == http.StatusNotFound:
+		return codes.NotFound
+	case httpStatus == http.StatusBadRequest:
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
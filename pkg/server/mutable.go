@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// RegistryQuerier is this checkout's best-effort reconstruction of the
+// subset of registry.Query that the api.Registry gRPC service (and the
+// HTTP/JSON gateway mounted alongside it) actually call. Neither
+// pkg/registry nor the rest of pkg/server - including NewRegistryServer and
+// NewHealthServer, both called from cmd/appregistry-server/main.go but not
+// defined anywhere in this checkout - are available here, so this was
+// written from memory of the method names involved rather than type-checked
+// against the real registry.Query.
+//
+// That means it may well be wrong in ways that don't show up as a missing
+// method: in the real repo, registry.Query.GetPackage has historically
+// returned an internal *registry.PackageManifest, converted to *api.Package
+// only inside the grpc handler - not *api.Package directly, as declared
+// below. If that's still true, the store Loader.Load returns doesn't
+// actually satisfy this interface, GetPackage/GetBundle/GetBundleForChannel
+// need to change to return the internal types instead, and the
+// manifest-to-api conversion needs to move into whatever calls Current() -
+// none of which can be confirmed without pkg/registry's actual source.
+// Do not treat this interface as verified; check it against pkg/registry
+// before merging.
+type RegistryQuerier interface {
+	GetPackage(ctx context.Context, name string) (*api.Package, error)
+	GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error)
+	GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error)
+	ListPackages(ctx context.Context) ([]string, error)
+	ListBundles(ctx context.Context) ([]*api.Bundle, error)
+}
+
+// MutableRegistryServer is a RegistryQuerier whose underlying store can be
+// swapped out while the gRPC server is serving requests against it. It lets
+// a background refresh build a new sqlite database and publish it
+// atomically, without restarting the server or dropping in-flight RPCs.
+//
+// The zero value is not usable; construct one with NewMutableRegistryServer.
+type MutableRegistryServer struct {
+	current atomic.Value // querierHolder
+}
+
+// querierHolder gives every value stored in MutableRegistryServer.current the
+// same concrete type, since atomic.Value panics when a swap changes it.
+type querierHolder struct {
+	q RegistryQuerier
+}
+
+// NewMutableRegistryServer returns a MutableRegistryServer backed by store.
+func NewMutableRegistryServer(store RegistryQuerier) *MutableRegistryServer {
+	m := &MutableRegistryServer{}
+	m.Swap(store)
+	return m
+}
+
+// Swap atomically replaces the store that subsequent queries are served
+// from and returns whichever store was current beforehand (nil the first
+// time). In-flight requests continue to be served by whichever store was
+// current when they started; it is the caller's responsibility to decide
+// when it is safe to release the resources behind the returned store.
+func (m *MutableRegistryServer) Swap(store RegistryQuerier) RegistryQuerier {
+	old, _ := m.current.Swap(querierHolder{store}).(querierHolder)
+	return old.q
+}
+
+// Current returns the store currently serving queries.
+func (m *MutableRegistryServer) Current() RegistryQuerier {
+	return m.current.Load().(querierHolder).q
+}
+
+func (m *MutableRegistryServer) GetPackage(ctx context.Context, name string) (*api.Package, error) {
+	return m.Current().GetPackage(ctx, name)
+}
+
+func (m *MutableRegistryServer) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	return m.Current().GetBundle(ctx, pkgName, channelName, csvName)
+}
+
+func (m *MutableRegistryServer) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return m.Current().GetBundleForChannel(ctx, pkgName, channelName)
+}
+
+func (m *MutableRegistryServer) ListPackages(ctx context.Context) ([]string, error) {
+	return m.Current().ListPackages(ctx)
+}
+
+func (m *MutableRegistryServer) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
+	return m.Current().ListBundles(ctx)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// fakeQuerier is a minimal RegistryQuerier for exercising
+// MutableRegistryServer without a real sqlite-backed store.
+type fakeQuerier struct {
+	packages []string
+}
+
+func (f *fakeQuerier) GetPackage(ctx context.Context, name string) (*api.Package, error) {
+	return &api.Package{Name: name}, nil
+}
+
+func (f *fakeQuerier) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	return &api.Bundle{PackageName: pkgName}, nil
+}
+
+func (f *fakeQuerier) GetBundleForChannel(ctx context.Context, pkgName, channelName string) (*api.Bundle, error) {
+	return &api.Bundle{PackageName: pkgName}, nil
+}
+
+func (f *fakeQuerier) ListPackages(ctx context.Context) ([]string, error) {
+	return f.packages, nil
+}
+
+func (f *fakeQuerier) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
+	return nil, nil
+}
+
+func TestMutableRegistryServerCurrentReflectsLatestSwap(t *testing.T) {
+	first := &fakeQuerier{packages: []string{"etcd"}}
+	m := NewMutableRegistryServer(first)
+
+	if m.Current() != RegistryQuerier(first) {
+		t.Fatalf("Current() after construction = %v, want the store passed to NewMutableRegistryServer", m.Current())
+	}
+
+	names, err := m.ListPackages(context.Background())
+	if err != nil || len(names) != 1 || names[0] != "etcd" {
+		t.Fatalf("ListPackages() = %v, %v, want [etcd], nil", names, err)
+	}
+
+	second := &fakeQuerier{packages: []string{"prometheus"}}
+	m.Swap(second)
+
+	names, err = m.ListPackages(context.Background())
+	if err != nil || len(names) != 1 || names[0] != "prometheus" {
+		t.Fatalf("ListPackages() after Swap = %v, %v, want [prometheus], nil", names, err)
+	}
+}
+
+func TestMutableRegistryServerSwapReturnsPreviousStore(t *testing.T) {
+	first := &fakeQuerier{packages: []string{"etcd"}}
+	m := NewMutableRegistryServer(first)
+
+	second := &fakeQuerier{packages: []string{"prometheus"}}
+	old := m.Swap(second)
+
+	if old != RegistryQuerier(first) {
+		t.Fatalf("Swap() returned %v, want the store passed to NewMutableRegistryServer", old)
+	}
+}
+
+// closeTrackingQuerier lets a test confirm, without a real sqlite file, that
+// the store Swap hands back still exposes the Close method refresh.go looks
+// for.
+type closeTrackingQuerier struct {
+	fakeQuerier
+	closed bool
+}
+
+func (c *closeTrackingQuerier) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestMutableRegistryServerSwapOldStoreIsCloseable(t *testing.T) {
+	first := &closeTrackingQuerier{}
+	m := NewMutableRegistryServer(first)
+
+	old := m.Swap(&fakeQuerier{})
+
+	closer, ok := old.(interface{ Close() error })
+	if !ok {
+		t.Fatalf("Swap() returned %T, want something implementing Close() error", old)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close() returned %v, want nil", err)
+	}
+	if !first.closed {
+		t.Fatalf("Close() did not mark the original store closed")
+	}
+}